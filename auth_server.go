@@ -0,0 +1,90 @@
+package vnc
+
+import (
+	"crypto/des"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/binary"
+)
+
+// ServerAuthNone implements security type 1 (None): the client is admitted
+// without any further negotiation.
+type ServerAuthNone struct{}
+
+// SecurityType returns the byte identifying no authentication.
+func (*ServerAuthNone) SecurityType() uint8 {
+	return 1
+}
+
+// Handshake is a no-op; there is nothing to negotiate for security type 1.
+func (*ServerAuthNone) Handshake(*ServerConn) error {
+	return nil
+}
+
+// ServerAuthVNC implements security type 2 (VNC Authentication): a 16-byte
+// DES challenge-response checked against ServerConfig.Passwords, the
+// server-side counterpart of ClientAuthVNC.
+type ServerAuthVNC struct{}
+
+// SecurityType returns the byte identifying VNC Authentication.
+func (*ServerAuthVNC) SecurityType() uint8 {
+	return 2
+}
+
+// Handshake sends a random 16-byte challenge and checks the client's
+// encrypted response against each of ServerConfig.Passwords in turn,
+// succeeding on the first match.
+func (*ServerAuthVNC) Handshake(c *ServerConn) error {
+	challenge := make([]byte, 16)
+	if _, err := rand.Read(challenge); err != nil {
+		return err
+	}
+	if err := binary.Write(c.c, binary.BigEndian, challenge); err != nil {
+		return err
+	}
+
+	response := make([]byte, 16)
+	if err := binary.Read(c.c, binary.BigEndian, response); err != nil {
+		return err
+	}
+
+	for _, password := range c.config.Passwords {
+		block, err := des.NewCipher(vncAuthKey(password))
+		if err != nil {
+			return err
+		}
+
+		expected := make([]byte, 16)
+		block.Encrypt(expected[0:8], challenge[0:8])
+		block.Encrypt(expected[8:16], challenge[8:16])
+
+		if subtle.ConstantTimeCompare(expected, response) == 1 {
+			return nil
+		}
+	}
+
+	return &SecurityResultError{Reason: "Authentication failure"}
+}
+
+// vncAuthKey derives the 8-byte DES key VNC Authentication uses from a
+// password: truncated or zero-padded to 8 bytes, with the bits of each
+// byte reversed, as specified by RFC 6143 section 7.2.2.
+func vncAuthKey(password string) []byte {
+	key := make([]byte, 8)
+	copy(key, password)
+	for i, b := range key {
+		key[i] = reverseBits(b)
+	}
+	return key
+}
+
+// reverseBits reverses the bit order of a single byte.
+func reverseBits(b byte) byte {
+	var r byte
+	for i := 0; i < 8; i++ {
+		r <<= 1
+		r |= b & 1
+		b >>= 1
+	}
+	return r
+}
@@ -0,0 +1,69 @@
+package vnc
+
+import (
+	"crypto/des"
+	"encoding/binary"
+	"net"
+	"testing"
+)
+
+func respondToChallenge(t *testing.T, client net.Conn, password string) {
+	t.Helper()
+
+	challenge := make([]byte, 16)
+	if err := binary.Read(client, binary.BigEndian, challenge); err != nil {
+		t.Fatalf("reading challenge: %v", err)
+	}
+
+	block, err := des.NewCipher(vncAuthKey(password))
+	if err != nil {
+		t.Fatalf("des.NewCipher: %v", err)
+	}
+
+	response := make([]byte, 16)
+	block.Encrypt(response[0:8], challenge[0:8])
+	block.Encrypt(response[8:16], challenge[8:16])
+
+	if err := binary.Write(client, binary.BigEndian, response); err != nil {
+		t.Fatalf("writing response: %v", err)
+	}
+}
+
+func TestServerAuthVNCHandshakeSuccess(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	conn := &ServerConn{c: server, config: &ServerConfig{Passwords: []string{"secret"}}}
+
+	done := make(chan error, 1)
+	go func() { done <- (&ServerAuthVNC{}).Handshake(conn) }()
+
+	respondToChallenge(t, client, "secret")
+
+	if err := <-done; err != nil {
+		t.Errorf("Handshake() = %v, want nil", err)
+	}
+}
+
+func TestServerAuthVNCHandshakeWrongPassword(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	conn := &ServerConn{c: server, config: &ServerConfig{Passwords: []string{"secret"}}}
+
+	done := make(chan error, 1)
+	go func() { done <- (&ServerAuthVNC{}).Handshake(conn) }()
+
+	respondToChallenge(t, client, "wrong")
+
+	err := <-done
+	secErr, ok := err.(*SecurityResultError)
+	if !ok {
+		t.Fatalf("Handshake() error type = %T, want *SecurityResultError", err)
+	}
+	if secErr.Reason == "" {
+		t.Errorf("SecurityResultError.Reason is empty, want a failure reason")
+	}
+}
@@ -0,0 +1,132 @@
+package vnc
+
+import (
+	"image"
+	"image/color"
+	"sync"
+)
+
+// Canvas is an in-memory framebuffer that Encoding.Read implementations
+// paint into as FramebufferUpdate rectangles arrive. It maintains the
+// current state of the remote desktop so that consumers such as the
+// recorder package can snapshot or stream it at any time. Painting and
+// reading happen from different goroutines in normal use (the connection's
+// read loop paints, a recorder reads on a timer), so all access goes
+// through mu.
+type Canvas struct {
+	mu sync.Mutex
+	*image.RGBA
+}
+
+// NewCanvas allocates a Canvas of the given dimensions, initialized to
+// opaque black.
+func NewCanvas(width, height int) *Canvas {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 0xff
+	}
+	return &Canvas{RGBA: img}
+}
+
+// Set paints a single pixel. It shadows image.RGBA's promoted Set method
+// so that every write is synchronized.
+func (c *Canvas) Set(x, y int, col color.Color) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.RGBA.Set(x, y, col)
+}
+
+// At returns the color of a single pixel. It shadows image.RGBA's promoted
+// At method so that every read is synchronized.
+func (c *Canvas) At(x, y int) color.Color {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.RGBA.At(x, y)
+}
+
+// Snapshot returns a copy of the canvas's current pixels, safe to encode
+// or stream concurrently with painting.
+func (c *Canvas) Snapshot() *image.RGBA {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	dup := image.NewRGBA(c.RGBA.Bounds())
+	copy(dup.Pix, c.RGBA.Pix)
+	return dup
+}
+
+// SetPixel paints a single pixel, translating raw encoded pixel data into
+// an RGBA color using the connection's PixelFormat and, if the format is
+// color-mapped, its ColorMap.
+func (c *Canvas) SetPixel(x, y int, pf *PixelFormat, colorMap *[256]Color, raw []byte) {
+	c.Set(x, y, decodePixel(pf, colorMap, raw))
+}
+
+// FillRect paints every pixel in rect with a single decoded color, as used
+// by encodings such as RRE and Hextile for solid sub-rectangles.
+func (c *Canvas) FillRect(rect *Rectangle, pf *PixelFormat, colorMap *[256]Color, raw []byte) {
+	col := decodePixel(pf, colorMap, raw)
+	x0, y0 := int(rect.X), int(rect.Y)
+	x1, y1 := x0+int(rect.Width), y0+int(rect.Height)
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			c.Set(x, y, col)
+		}
+	}
+}
+
+// CopyRect copies a rectangle of pixels already present on the canvas from
+// one location to another, as used by the CopyRect encoding.
+func (c *Canvas) CopyRect(dst *Rectangle, srcX, srcY int) {
+	x0, y0 := int(dst.X), int(dst.Y)
+	w, h := int(dst.Width), int(dst.Height)
+
+	src := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			src.Set(x, y, c.At(srcX+x, srcY+y))
+		}
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c.Set(x0+x, y0+y, src.At(x, y))
+		}
+	}
+}
+
+// decodePixel converts a single raw pixel, encoded per pf, into a
+// color.RGBA, resolving color-map indices against colorMap when pf.TrueColor
+// is false.
+func decodePixel(pf *PixelFormat, colorMap *[256]Color, raw []byte) color.RGBA {
+	if !pf.TrueColor {
+		idx := raw[0]
+		if colorMap == nil {
+			return color.RGBA{A: 0xff}
+		}
+		col := colorMap[idx]
+		return color.RGBA{
+			R: uint8(col.R >> 8),
+			G: uint8(col.G >> 8),
+			B: uint8(col.B >> 8),
+			A: 0xff,
+		}
+	}
+
+	n := int(pf.BPP / 8)
+	var pixel uint32
+	if pf.BigEndian {
+		for _, b := range raw[:n] {
+			pixel = pixel<<8 | uint32(b)
+		}
+	} else {
+		for i := n - 1; i >= 0; i-- {
+			pixel = pixel<<8 | uint32(raw[i])
+		}
+	}
+
+	r := uint8((pixel >> pf.RedShift) & uint32(pf.RedMax) * 255 / uint32(pf.RedMax))
+	g := uint8((pixel >> pf.GreenShift) & uint32(pf.GreenMax) * 255 / uint32(pf.GreenMax))
+	b := uint8((pixel >> pf.BlueShift) & uint32(pf.BlueMax) * 255 / uint32(pf.BlueMax))
+
+	return color.RGBA{R: r, G: g, B: b, A: 0xff}
+}
@@ -0,0 +1,499 @@
+package vnc
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"io"
+)
+
+const (
+	copyRectEnc int32 = 1
+	rreEnc      int32 = 2
+	hextileEnc  int32 = 5
+	tightEnc    int32 = 7
+	zrleEnc     int32 = 16
+)
+
+// CopyRectEncoding represents the CopyRect encoding, which instructs the
+// client to copy a rectangle of pixels already present on the canvas from
+// one location to another rather than resending the pixel data.
+type CopyRectEncoding struct{}
+
+// Type returns the int32 identifier for the CopyRect encoding.
+func (*CopyRectEncoding) Type() int32 { return copyRectEnc }
+
+// Read implements the CopyRect encoding, reading the source coordinates and
+// copying the pixels already on conn.Canvas into rect.
+func (*CopyRectEncoding) Read(conn *ClientConn, rect *Rectangle, r io.Reader) (Encoding, error) {
+	var srcX, srcY uint16
+	if err := binary.Read(r, binary.BigEndian, &srcX); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(r, binary.BigEndian, &srcY); err != nil {
+		return nil, err
+	}
+
+	if conn.Canvas != nil {
+		conn.Canvas.CopyRect(rect, int(srcX), int(srcY))
+	}
+
+	return &CopyRectEncoding{}, nil
+}
+
+// RREEncoding represents the "Rise-and-Run-length Encoding": a background
+// color covering the whole rectangle, followed by a list of foreground
+// sub-rectangles painted on top of it.
+type RREEncoding struct{}
+
+// Type returns the int32 identifier for the RRE encoding.
+func (*RREEncoding) Type() int32 { return rreEnc }
+
+// Read implements the RRE encoding.
+func (*RREEncoding) Read(conn *ClientConn, rect *Rectangle, r io.Reader) (Encoding, error) {
+	var numSubrects uint32
+	if err := binary.Read(r, binary.BigEndian, &numSubrects); err != nil {
+		return nil, err
+	}
+
+	bytesPerPixel := int(conn.PixelFormat.BPP / 8)
+	bg := make([]byte, bytesPerPixel)
+	if _, err := io.ReadFull(r, bg); err != nil {
+		return nil, err
+	}
+	if conn.Canvas != nil {
+		conn.Canvas.FillRect(rect, &conn.PixelFormat, &conn.ColorMap, bg)
+	}
+
+	for i := uint32(0); i < numSubrects; i++ {
+		pixel := make([]byte, bytesPerPixel)
+		if _, err := io.ReadFull(r, pixel); err != nil {
+			return nil, err
+		}
+
+		var x, y, w, h uint16
+		for _, f := range []*uint16{&x, &y, &w, &h} {
+			if err := binary.Read(r, binary.BigEndian, f); err != nil {
+				return nil, err
+			}
+		}
+
+		if conn.Canvas != nil {
+			sub := &Rectangle{X: x + rect.X, Y: y + rect.Y, Width: w, Height: h}
+			conn.Canvas.FillRect(sub, &conn.PixelFormat, &conn.ColorMap, pixel)
+		}
+	}
+
+	return &RREEncoding{}, nil
+}
+
+// hextile subencoding mask bits, as defined in [PROTOCOL] section 7.7.3.
+const (
+	hextileRaw                 = 1 << 0
+	hextileBackgroundSpecified = 1 << 1
+	hextileForegroundSpecified = 1 << 2
+	hextileAnySubrects         = 1 << 3
+	hextileSubrectsColoured    = 1 << 4
+)
+
+// HextileEncoding divides the rectangle into 16x16 tiles, each of which is
+// either sent raw or as a background color plus a list of (optionally
+// individually colored) foreground sub-rectangles.
+type HextileEncoding struct{}
+
+// Type returns the int32 identifier for the Hextile encoding.
+func (*HextileEncoding) Type() int32 { return hextileEnc }
+
+// Read implements the Hextile encoding.
+func (*HextileEncoding) Read(conn *ClientConn, rect *Rectangle, r io.Reader) (Encoding, error) {
+	bytesPerPixel := int(conn.PixelFormat.BPP / 8)
+	bg := make([]byte, bytesPerPixel)
+	fg := make([]byte, bytesPerPixel)
+
+	for ty := uint16(0); ty < rect.Height; ty += 16 {
+		th := min16(16, rect.Height-ty)
+		for tx := uint16(0); tx < rect.Width; tx += 16 {
+			tw := min16(16, rect.Width-tx)
+			tile := &Rectangle{X: rect.X + tx, Y: rect.Y + ty, Width: tw, Height: th}
+
+			var mask uint8
+			if err := binary.Read(r, binary.BigEndian, &mask); err != nil {
+				return nil, err
+			}
+
+			if mask&hextileRaw != 0 {
+				raw := make([]byte, int(tw)*int(th)*bytesPerPixel)
+				if _, err := io.ReadFull(r, raw); err != nil {
+					return nil, err
+				}
+				if conn.Canvas != nil {
+					for y := 0; y < int(th); y++ {
+						for x := 0; x < int(tw); x++ {
+							off := (y*int(tw) + x) * bytesPerPixel
+							conn.Canvas.SetPixel(int(tile.X)+x, int(tile.Y)+y, &conn.PixelFormat, &conn.ColorMap, raw[off:off+bytesPerPixel])
+						}
+					}
+				}
+				continue
+			}
+
+			if mask&hextileBackgroundSpecified != 0 {
+				if _, err := io.ReadFull(r, bg); err != nil {
+					return nil, err
+				}
+			}
+			if conn.Canvas != nil {
+				conn.Canvas.FillRect(tile, &conn.PixelFormat, &conn.ColorMap, bg)
+			}
+
+			if mask&hextileForegroundSpecified != 0 {
+				if _, err := io.ReadFull(r, fg); err != nil {
+					return nil, err
+				}
+			}
+
+			if mask&hextileAnySubrects != 0 {
+				var numSubrects uint8
+				if err := binary.Read(r, binary.BigEndian, &numSubrects); err != nil {
+					return nil, err
+				}
+
+				for i := uint8(0); i < numSubrects; i++ {
+					pixel := fg
+					if mask&hextileSubrectsColoured != 0 {
+						pixel = make([]byte, bytesPerPixel)
+						if _, err := io.ReadFull(r, pixel); err != nil {
+							return nil, err
+						}
+					}
+
+					var xy, wh uint8
+					if err := binary.Read(r, binary.BigEndian, &xy); err != nil {
+						return nil, err
+					}
+					if err := binary.Read(r, binary.BigEndian, &wh); err != nil {
+						return nil, err
+					}
+
+					sub := &Rectangle{
+						X:      tile.X + uint16(xy>>4),
+						Y:      tile.Y + uint16(xy&0x0f),
+						Width:  uint16(wh>>4) + 1,
+						Height: uint16(wh&0x0f) + 1,
+					}
+					if conn.Canvas != nil {
+						conn.Canvas.FillRect(sub, &conn.PixelFormat, &conn.ColorMap, pixel)
+					}
+				}
+			}
+		}
+	}
+
+	return &HextileEncoding{}, nil
+}
+
+func min16(a, b uint16) uint16 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// ZRLEEncoding implements the zlib Run-Length Encoding: the rectangle is
+// split into 64x64 tiles, read from a single zlib stream that spans every
+// rectangle of every FramebufferUpdate for the lifetime of the connection,
+// each tile itself being raw, solid, packed-palette, or RLE (optionally
+// palette-indexed) data. A ZRLEEncoding value must be reused across reads
+// (FramebufferUpdateMessage.Read reuses the one held in
+// ClientConn.Encodings) rather than recreated per rectangle, or the zlib
+// stream desyncs.
+type ZRLEEncoding struct {
+	buf *bytes.Buffer
+	zr  io.Reader
+}
+
+// Type returns the int32 identifier for the ZRLE encoding.
+func (*ZRLEEncoding) Type() int32 { return zrleEnc }
+
+// Read implements the ZRLE encoding.
+func (e *ZRLEEncoding) Read(conn *ClientConn, rect *Rectangle, r io.Reader) (Encoding, error) {
+	var length uint32
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+
+	compressed := make([]byte, length)
+	if _, err := io.ReadFull(r, compressed); err != nil {
+		return nil, err
+	}
+
+	// The zlib stream is continuous across rectangles, so every chunk is
+	// appended to the same buffer that the single, persistent zlib.Reader
+	// reads from; creating a fresh zlib.Reader per rectangle (or per
+	// chunk) would desync the stream after the first one.
+	if e.buf == nil {
+		e.buf = new(bytes.Buffer)
+	}
+	e.buf.Write(compressed)
+
+	if e.zr == nil {
+		zr, err := zlib.NewReader(e.buf)
+		if err != nil {
+			return nil, err
+		}
+		e.zr = zr
+	}
+
+	bytesPerPixel := int(conn.PixelFormat.BPP / 8)
+	for ty := uint16(0); ty < rect.Height; ty += 64 {
+		th := min16(64, rect.Height-ty)
+		for tx := uint16(0); tx < rect.Width; tx += 64 {
+			tw := min16(64, rect.Width-tx)
+
+			var subencoding uint8
+			if err := binary.Read(e.zr, binary.BigEndian, &subencoding); err != nil {
+				return nil, err
+			}
+
+			tile := &Rectangle{X: rect.X + tx, Y: rect.Y + ty, Width: tw, Height: th}
+
+			switch {
+			case subencoding == 0:
+				raw := make([]byte, int(tw)*int(th)*bytesPerPixel)
+				if _, err := io.ReadFull(e.zr, raw); err != nil {
+					return nil, err
+				}
+				if conn.Canvas != nil {
+					for y := 0; y < int(th); y++ {
+						for x := 0; x < int(tw); x++ {
+							off := (y*int(tw) + x) * bytesPerPixel
+							conn.Canvas.SetPixel(int(tile.X)+x, int(tile.Y)+y, &conn.PixelFormat, &conn.ColorMap, raw[off:off+bytesPerPixel])
+						}
+					}
+				}
+			case subencoding == 1:
+				pixel := make([]byte, bytesPerPixel)
+				if _, err := io.ReadFull(e.zr, pixel); err != nil {
+					return nil, err
+				}
+				if conn.Canvas != nil {
+					conn.Canvas.FillRect(tile, &conn.PixelFormat, &conn.ColorMap, pixel)
+				}
+			case subencoding >= 2 && subencoding <= 16:
+				palette, err := readZRLEPalette(e.zr, int(subencoding), bytesPerPixel)
+				if err != nil {
+					return nil, err
+				}
+				if err := readZRLEPacked(e.zr, conn, tile, palette); err != nil {
+					return nil, err
+				}
+			case subencoding == 128:
+				if err := readZRLERLE(e.zr, conn, tile, bytesPerPixel, nil); err != nil {
+					return nil, err
+				}
+			case subencoding >= 130:
+				palette, err := readZRLEPalette(e.zr, int(subencoding)-128, bytesPerPixel)
+				if err != nil {
+					return nil, err
+				}
+				if err := readZRLERLE(e.zr, conn, tile, bytesPerPixel, palette); err != nil {
+					return nil, err
+				}
+			default:
+				return nil, NewVNCError("ZRLE: reserved tile subencoding")
+			}
+		}
+	}
+
+	return e, nil
+}
+
+// readZRLEPalette reads the size-entry color palette that precedes a
+// packed-palette or palette-RLE ZRLE tile.
+func readZRLEPalette(r io.Reader, size, bytesPerPixel int) ([][]byte, error) {
+	palette := make([][]byte, size)
+	for i := range palette {
+		palette[i] = make([]byte, bytesPerPixel)
+		if _, err := io.ReadFull(r, palette[i]); err != nil {
+			return nil, err
+		}
+	}
+	return palette, nil
+}
+
+// readZRLEPacked reads a packed-palette tile: each row is an array of
+// palette indices packed MSB-first, at a bits-per-pixel determined by the
+// palette size, padded to a whole number of bytes per row.
+func readZRLEPacked(r io.Reader, conn *ClientConn, tile *Rectangle, palette [][]byte) error {
+	bpp := zrlePackedBPP(len(palette))
+	rowBytes := (int(tile.Width)*bpp + 7) / 8
+	row := make([]byte, rowBytes)
+
+	for y := 0; y < int(tile.Height); y++ {
+		if _, err := io.ReadFull(r, row); err != nil {
+			return err
+		}
+		if conn.Canvas == nil {
+			continue
+		}
+		for x := 0; x < int(tile.Width); x++ {
+			idx := zrleUnpackIndex(row, x, bpp)
+			conn.Canvas.SetPixel(int(tile.X)+x, int(tile.Y)+y, &conn.PixelFormat, &conn.ColorMap, palette[idx])
+		}
+	}
+	return nil
+}
+
+// zrlePackedBPP returns the bits-per-pixel used to pack indices into a
+// palette of the given size, per [PROTOCOL] section 7.7.5.
+func zrlePackedBPP(paletteSize int) int {
+	switch {
+	case paletteSize <= 2:
+		return 1
+	case paletteSize <= 4:
+		return 2
+	default:
+		return 4
+	}
+}
+
+// zrleUnpackIndex extracts the bpp-wide, MSB-first palette index for pixel
+// x of a packed-palette row.
+func zrleUnpackIndex(row []byte, x, bpp int) int {
+	bitPos := x * bpp
+	shift := 8 - bpp - bitPos%8
+	mask := (1 << uint(bpp)) - 1
+	return int(row[bitPos/8]>>uint(shift)) & mask
+}
+
+// readZRLERLE reads a plain (palette == nil) or palette-indexed RLE tile:
+// a sequence of runs, each a pixel (or palette index) plus a run length,
+// until the tile's pixels are exhausted.
+func readZRLERLE(r io.Reader, conn *ClientConn, tile *Rectangle, bytesPerPixel int, palette [][]byte) error {
+	total := int(tile.Width) * int(tile.Height)
+
+	for painted := 0; painted < total; {
+		var pixel []byte
+		var runLen int
+
+		if palette == nil {
+			pixel = make([]byte, bytesPerPixel)
+			if _, err := io.ReadFull(r, pixel); err != nil {
+				return err
+			}
+			n, err := readZRLERunLength(r)
+			if err != nil {
+				return err
+			}
+			runLen = n
+		} else {
+			var b uint8
+			if err := binary.Read(r, binary.BigEndian, &b); err != nil {
+				return err
+			}
+			pixel = palette[b&0x7f]
+			runLen = 1
+			if b&0x80 != 0 {
+				n, err := readZRLERunLength(r)
+				if err != nil {
+					return err
+				}
+				runLen = n
+			}
+		}
+
+		if runLen > total-painted {
+			runLen = total - painted
+		}
+
+		if conn.Canvas != nil {
+			for i := 0; i < runLen; i++ {
+				p := painted + i
+				x := int(tile.X) + p%int(tile.Width)
+				y := int(tile.Y) + p/int(tile.Width)
+				conn.Canvas.SetPixel(x, y, &conn.PixelFormat, &conn.ColorMap, pixel)
+			}
+		}
+		painted += runLen
+	}
+	return nil
+}
+
+// readZRLERunLength reads a ZRLE RLE run length: a sequence of bytes that
+// are each 255 while more remain, terminated by a byte less than 255; the
+// run length is one more than the sum of all bytes read.
+func readZRLERunLength(r io.Reader) (int, error) {
+	length := 0
+	for {
+		var b uint8
+		if err := binary.Read(r, binary.BigEndian, &b); err != nil {
+			return 0, err
+		}
+		length += int(b)
+		if b != 255 {
+			break
+		}
+	}
+	return length + 1, nil
+}
+
+// TightEncoding implements the subset of the Tight encoding used for
+// already-compressed (JPEG) tiles, which is how most real-world servers
+// fall back when a region changes too much for RLE-style compression to
+// help. Basic (zlib) compression-control tiles are not implemented.
+type TightEncoding struct{}
+
+// Type returns the int32 identifier for the Tight encoding.
+func (*TightEncoding) Type() int32 { return tightEnc }
+
+// Read implements the Tight encoding's JPEG compression-control path,
+// decoding the embedded JPEG straight into conn.Canvas.
+func (*TightEncoding) Read(conn *ClientConn, rect *Rectangle, r io.Reader) (Encoding, error) {
+	var compControl uint8
+	if err := binary.Read(r, binary.BigEndian, &compControl); err != nil {
+		return nil, err
+	}
+
+	// The compression type occupies the high nibble of the control byte;
+	// the low nibble carries zlib-stream-reset flags that don't affect
+	// which compression type is in use.
+	const tightJPEG = 0x09
+	if compControl>>4 != tightJPEG {
+		return nil, NewVNCError("Tight: only JPEG-compressed tiles are supported")
+	}
+
+	var length uint32
+	if err := readTightLength(r, &length); err != nil {
+		return nil, err
+	}
+
+	jpegData := make([]byte, length)
+	if _, err := io.ReadFull(r, jpegData); err != nil {
+		return nil, err
+	}
+
+	if err := decodeJPEGInto(conn.Canvas, rect, jpegData); err != nil {
+		return nil, err
+	}
+
+	return &TightEncoding{}, nil
+}
+
+// readTightLength reads a Tight-encoding compact length: 1-3 bytes, each
+// contributing 7 bits, with the high bit of each byte signalling whether
+// another byte follows.
+func readTightLength(r io.Reader, out *uint32) error {
+	var result uint32
+	for shift := uint(0); shift < 21; shift += 7 {
+		var b uint8
+		if err := binary.Read(r, binary.BigEndian, &b); err != nil {
+			return err
+		}
+		result |= uint32(b&0x7f) << shift
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	*out = result
+	return nil
+}
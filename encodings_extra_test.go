@@ -0,0 +1,108 @@
+package vnc
+
+import (
+	"bytes"
+	"image/color"
+	"testing"
+)
+
+// truecolor24PixelFormat is a typical 32bpp/24-depth true-color format
+// (8 bits per channel, red in the high byte), used to turn known wire
+// bytes into known colors for the encoding tests below.
+func truecolor24PixelFormat(bigEndian bool) PixelFormat {
+	return PixelFormat{
+		BPP:        32,
+		Depth:      24,
+		BigEndian:  bigEndian,
+		TrueColor:  true,
+		RedMax:     255,
+		GreenMax:   255,
+		BlueMax:    255,
+		RedShift:   16,
+		GreenShift: 8,
+		BlueShift:  0,
+	}
+}
+
+func TestDecodePixelHonorsByteOrder(t *testing.T) {
+	want := color.RGBA{R: 0xaa, G: 0xbb, B: 0xcc, A: 0xff}
+
+	bigEndianRaw := []byte{0x00, 0xaa, 0xbb, 0xcc}
+	pfBE := truecolor24PixelFormat(true)
+	if got := decodePixel(&pfBE, nil, bigEndianRaw); got != want {
+		t.Errorf("decodePixel(big-endian) = %+v, want %+v", got, want)
+	}
+
+	littleEndianRaw := []byte{0xcc, 0xbb, 0xaa, 0x00}
+	pfLE := truecolor24PixelFormat(false)
+	if got := decodePixel(&pfLE, nil, littleEndianRaw); got != want {
+		t.Errorf("decodePixel(little-endian) = %+v, want %+v", got, want)
+	}
+}
+
+func TestCopyRectEncodingRead(t *testing.T) {
+	conn := &ClientConn{Canvas: NewCanvas(4, 4)}
+	conn.Canvas.Set(0, 0, color.RGBA{R: 0xff, A: 0xff})
+
+	e := &CopyRectEncoding{}
+	rect := &Rectangle{X: 2, Y: 2, Width: 1, Height: 1}
+	src := []byte{0, 0, 0, 0} // srcX=0, srcY=0
+
+	if _, err := e.Read(conn, rect, bytes.NewReader(src)); err != nil {
+		t.Fatalf("Read() = %v", err)
+	}
+
+	want := color.RGBA{R: 0xff, A: 0xff}
+	if got := conn.Canvas.At(2, 2); got != want {
+		t.Errorf("Canvas.At(2, 2) = %+v, want %+v", got, want)
+	}
+}
+
+func TestRREEncodingReadFillsBackground(t *testing.T) {
+	pf := truecolor24PixelFormat(true)
+	conn := &ClientConn{Canvas: NewCanvas(4, 4), PixelFormat: pf}
+
+	e := &RREEncoding{}
+	rect := &Rectangle{X: 0, Y: 0, Width: 4, Height: 4}
+
+	var data bytes.Buffer
+	data.Write([]byte{0, 0, 0, 0})             // numSubrects = 0
+	data.Write([]byte{0x00, 0xff, 0x00, 0x00}) // background pixel: pure red
+
+	if _, err := e.Read(conn, rect, &data); err != nil {
+		t.Fatalf("Read() = %v", err)
+	}
+
+	want := color.RGBA{R: 0xff, A: 0xff}
+	if got := conn.Canvas.At(1, 1); got != want {
+		t.Errorf("Canvas.At(1, 1) = %+v, want %+v", got, want)
+	}
+	if got := conn.Canvas.At(3, 3); got != want {
+		t.Errorf("Canvas.At(3, 3) = %+v, want %+v", got, want)
+	}
+}
+
+func TestRREEncodingReadPaintsSubrect(t *testing.T) {
+	pf := truecolor24PixelFormat(true)
+	conn := &ClientConn{Canvas: NewCanvas(4, 4), PixelFormat: pf}
+
+	e := &RREEncoding{}
+	rect := &Rectangle{X: 0, Y: 0, Width: 4, Height: 4}
+
+	var data bytes.Buffer
+	data.Write([]byte{0, 0, 0, 1})             // numSubrects = 1
+	data.Write([]byte{0x00, 0x00, 0x00, 0xff}) // background pixel: pure blue
+	data.Write([]byte{0x00, 0xff, 0x00, 0x00}) // subrect pixel: pure red
+	data.Write([]byte{0, 1, 0, 1, 0, 2, 0, 2}) // x=1, y=1, w=2, h=2
+
+	if _, err := e.Read(conn, rect, &data); err != nil {
+		t.Fatalf("Read() = %v", err)
+	}
+
+	if got, want := conn.Canvas.At(0, 0), (color.RGBA{B: 0xff, A: 0xff}); got != want {
+		t.Errorf("Canvas.At(0, 0) = %+v, want %+v", got, want)
+	}
+	if got, want := conn.Canvas.At(1, 1), (color.RGBA{R: 0xff, A: 0xff}); got != want {
+		t.Errorf("Canvas.At(1, 1) = %+v, want %+v", got, want)
+	}
+}
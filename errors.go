@@ -0,0 +1,33 @@
+package vnc
+
+import "errors"
+
+// Errors returned by ClientConn.ListenAndHandle, distinguishing why the
+// read loop stopped instead of the caller having to string-match a
+// generic VNCError.
+var (
+	// ErrUnsupportedMessage is returned when the server sends a message
+	// type not present in ClientConfig.ServerMessages. It is also returned
+	// by FramebufferUpdateMessage.Read for a rectangle whose encoding type
+	// it does not decode.
+	ErrUnsupportedMessage = errors.New("vnc: unsupported message type")
+
+	// ErrProtocol is returned when a message fails to parse according
+	// to the VNC wire format.
+	ErrProtocol = errors.New("vnc: protocol error")
+)
+
+// A VNCError implements error and represents the errors specified in the
+// RFC.
+type VNCError struct {
+	msg string
+}
+
+func (e VNCError) Error() string {
+	return e.msg
+}
+
+// NewVNCError returns a new VNCError with the given message.
+func NewVNCError(msg string) VNCError {
+	return VNCError{msg}
+}
@@ -0,0 +1,29 @@
+package vnc
+
+import (
+	"bytes"
+	"image"
+	"image/draw"
+	"image/jpeg"
+)
+
+// decodeJPEGInto decodes a JPEG tile, as used by Tight-encoded rectangles,
+// and draws it onto canvas at rect's offset.
+func decodeJPEGInto(canvas *Canvas, rect *Rectangle, data []byte) error {
+	if canvas == nil {
+		return nil
+	}
+
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	dst := image.Rect(int(rect.X), int(rect.Y), int(rect.X)+int(rect.Width), int(rect.Y)+int(rect.Height))
+
+	canvas.mu.Lock()
+	defer canvas.mu.Unlock()
+	draw.Draw(canvas.RGBA, dst, img, image.Point{}, draw.Src)
+
+	return nil
+}
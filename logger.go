@@ -0,0 +1,51 @@
+package vnc
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// A Logger receives diagnostic output from ClientConn and ServerConn, so
+// that libraries embedding this package into a server or proxy can route
+// it wherever they like instead of it going straight to stdout.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// noopLogger discards everything. It is the default Logger for a
+// ClientConfig/ServerConfig that doesn't set one.
+type noopLogger struct{}
+
+func (noopLogger) Debugf(string, ...interface{}) {}
+func (noopLogger) Infof(string, ...interface{})  {}
+func (noopLogger) Warnf(string, ...interface{})  {}
+func (noopLogger) Errorf(string, ...interface{}) {}
+
+// SlogLogger adapts a *slog.Logger to the Logger interface.
+type SlogLogger struct {
+	L *slog.Logger
+}
+
+// NewSlogLogger returns a Logger that writes to l.
+func NewSlogLogger(l *slog.Logger) *SlogLogger {
+	return &SlogLogger{L: l}
+}
+
+func (s *SlogLogger) Debugf(format string, args ...interface{}) {
+	s.L.Debug(fmt.Sprintf(format, args...))
+}
+
+func (s *SlogLogger) Infof(format string, args ...interface{}) {
+	s.L.Info(fmt.Sprintf(format, args...))
+}
+
+func (s *SlogLogger) Warnf(format string, args ...interface{}) {
+	s.L.Warn(fmt.Sprintf(format, args...))
+}
+
+func (s *SlogLogger) Errorf(format string, args ...interface{}) {
+	s.L.Error(fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,276 @@
+package vnc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+const (
+	framebufferUpdateMsgType  uint8 = 0
+	setColorMapEntriesMsgType uint8 = 1
+	bellMsgType               uint8 = 2
+	serverCutTextMsgType      uint8 = 3
+)
+
+// encodingByType returns a fresh Encoding instance for the given
+// FramebufferUpdate rectangle's encoding type, or nil if it's unsupported.
+func encodingByType(t int32) Encoding {
+	switch t {
+	case copyRectEnc:
+		return &CopyRectEncoding{}
+	case rreEnc:
+		return &RREEncoding{}
+	case hextileEnc:
+		return &HextileEncoding{}
+	case zrleEnc:
+		return &ZRLEEncoding{}
+	case tightEnc:
+		return &TightEncoding{}
+	default:
+		return nil
+	}
+}
+
+// rawRectangle pairs a Rectangle's header with the still-encoded bytes
+// that followed it on the wire. Keeping the raw bytes, not just the
+// decoded pixels, lets FramebufferUpdateMessage.Write forward a rectangle
+// unchanged without needing to know how to re-encode every Encoding.
+type rawRectangle struct {
+	Rectangle
+	EncodingType int32
+	data         []byte
+}
+
+// A FramebufferUpdateMessage is sent by the server whenever a region of
+// the framebuffer has changed.
+type FramebufferUpdateMessage struct {
+	rects []rawRectangle
+}
+
+// NewFramebufferUpdateMessage returns a FramebufferUpdateMessage. A nil or
+// empty rects only serves as a template for its Type and Read methods, as
+// ClientConfig.ServerMessages expects; Read always returns a freshly
+// populated message.
+func NewFramebufferUpdateMessage(rects []Rectangle) *FramebufferUpdateMessage {
+	msg := &FramebufferUpdateMessage{}
+	for _, r := range rects {
+		msg.rects = append(msg.rects, rawRectangle{Rectangle: r})
+	}
+	return msg
+}
+
+// Type returns the message-type byte for FramebufferUpdate.
+func (*FramebufferUpdateMessage) Type() uint8 { return framebufferUpdateMsgType }
+
+// Read parses a FramebufferUpdate: a count of rectangles, each a header
+// (position, size, encoding type) followed by encoding-specific data. The
+// matching Encoding paints conn.Canvas as it consumes that data; the exact
+// bytes it reads are captured alongside so Write can later forward the
+// rectangle unchanged.
+func (*FramebufferUpdateMessage) Read(conn *ClientConn, r io.Reader) (ServerMessage, error) {
+	var padding uint8
+	if err := binary.Read(r, binary.BigEndian, &padding); err != nil {
+		return nil, err
+	}
+
+	var numRects uint16
+	if err := binary.Read(r, binary.BigEndian, &numRects); err != nil {
+		return nil, err
+	}
+
+	msg := &FramebufferUpdateMessage{rects: make([]rawRectangle, 0, numRects)}
+
+	for i := uint16(0); i < numRects; i++ {
+		var rect Rectangle
+		for _, f := range []*uint16{&rect.X, &rect.Y, &rect.Width, &rect.Height} {
+			if err := binary.Read(r, binary.BigEndian, f); err != nil {
+				return nil, err
+			}
+		}
+
+		var encodingType int32
+		if err := binary.Read(r, binary.BigEndian, &encodingType); err != nil {
+			return nil, err
+		}
+
+		// Prefer the instance held in conn.Encodings, so that an encoding
+		// carrying state across rectangles (ZRLE's zlib stream) sees every
+		// rectangle of its type.
+		var enc Encoding
+		for _, e := range conn.Encodings {
+			if e.Type() == encodingType {
+				enc = e
+				break
+			}
+		}
+		if enc == nil {
+			enc = encodingByType(encodingType)
+		}
+		if enc == nil {
+			return nil, ErrUnsupportedMessage
+		}
+
+		var captured bytes.Buffer
+		if _, err := enc.Read(conn, &rect, io.TeeReader(r, &captured)); err != nil {
+			return nil, err
+		}
+
+		msg.rects = append(msg.rects, rawRectangle{
+			Rectangle:    rect,
+			EncodingType: encodingType,
+			data:         captured.Bytes(),
+		})
+	}
+
+	return msg, nil
+}
+
+// Write re-serializes the FramebufferUpdate exactly as it was received,
+// replaying each rectangle's captured raw bytes rather than re-encoding
+// pixel data, so a Proxy can forward it to a downstream viewer unchanged.
+func (m *FramebufferUpdateMessage) Write(w io.Writer) error {
+	if err := binary.Write(w, binary.BigEndian, framebufferUpdateMsgType); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, uint8(0)); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, uint16(len(m.rects))); err != nil {
+		return err
+	}
+
+	for _, rect := range m.rects {
+		for _, f := range []uint16{rect.X, rect.Y, rect.Width, rect.Height} {
+			if err := binary.Write(w, binary.BigEndian, f); err != nil {
+				return err
+			}
+		}
+		if err := binary.Write(w, binary.BigEndian, rect.EncodingType); err != nil {
+			return err
+		}
+		if _, err := w.Write(rect.data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// A SetColorMapEntriesMessage sets a range of entries in the client's
+// color map. It only applies when the negotiated PixelFormat is not
+// true-color.
+type SetColorMapEntriesMessage struct {
+	FirstColor uint16
+	Colors     []Color
+}
+
+// Type returns the message-type byte for SetColorMapEntries.
+func (*SetColorMapEntriesMessage) Type() uint8 { return setColorMapEntriesMsgType }
+
+// Read parses a SetColorMapEntries message, applying it to conn.ColorMap.
+func (*SetColorMapEntriesMessage) Read(conn *ClientConn, r io.Reader) (ServerMessage, error) {
+	var padding uint8
+	if err := binary.Read(r, binary.BigEndian, &padding); err != nil {
+		return nil, err
+	}
+
+	msg := &SetColorMapEntriesMessage{}
+	if err := binary.Read(r, binary.BigEndian, &msg.FirstColor); err != nil {
+		return nil, err
+	}
+
+	var numColors uint16
+	if err := binary.Read(r, binary.BigEndian, &numColors); err != nil {
+		return nil, err
+	}
+
+	msg.Colors = make([]Color, numColors)
+	for i := range msg.Colors {
+		if err := binary.Read(r, binary.BigEndian, &msg.Colors[i]); err != nil {
+			return nil, err
+		}
+		conn.ColorMap[int(msg.FirstColor)+i] = msg.Colors[i]
+	}
+
+	return msg, nil
+}
+
+// Write re-serializes a SetColorMapEntries message.
+func (m *SetColorMapEntriesMessage) Write(w io.Writer) error {
+	if err := binary.Write(w, binary.BigEndian, setColorMapEntriesMsgType); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, uint8(0)); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, m.FirstColor); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, uint16(len(m.Colors))); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.BigEndian, m.Colors)
+}
+
+// A BellMessage requests that the client produce an audible bell.
+type BellMessage struct{}
+
+// Type returns the message-type byte for Bell.
+func (*BellMessage) Type() uint8 { return bellMsgType }
+
+// Read parses a Bell message, which carries no payload beyond the type
+// byte already consumed by the caller.
+func (*BellMessage) Read(conn *ClientConn, r io.Reader) (ServerMessage, error) {
+	return &BellMessage{}, nil
+}
+
+// Write re-serializes a Bell message.
+func (*BellMessage) Write(w io.Writer) error {
+	return binary.Write(w, binary.BigEndian, bellMsgType)
+}
+
+// A ServerCutTextMessage indicates the server's cut-text (clipboard)
+// buffer has changed.
+type ServerCutTextMessage struct {
+	Text string
+}
+
+// Type returns the message-type byte for ServerCutText.
+func (*ServerCutTextMessage) Type() uint8 { return serverCutTextMsgType }
+
+// Read parses a ServerCutText message.
+func (*ServerCutTextMessage) Read(conn *ClientConn, r io.Reader) (ServerMessage, error) {
+	var padding [3]byte
+	if err := binary.Read(r, binary.BigEndian, &padding); err != nil {
+		return nil, err
+	}
+
+	var length uint32
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+
+	text := make([]byte, length)
+	if err := binary.Read(r, binary.BigEndian, text); err != nil {
+		return nil, err
+	}
+
+	return &ServerCutTextMessage{Text: string(text)}, nil
+}
+
+// Write re-serializes a ServerCutText message.
+func (m *ServerCutTextMessage) Write(w io.Writer) error {
+	if err := binary.Write(w, binary.BigEndian, serverCutTextMsgType); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, [3]byte{}); err != nil {
+		return err
+	}
+
+	text := []byte(m.Text)
+	if err := binary.Write(w, binary.BigEndian, uint32(len(text))); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.BigEndian, text)
+}
@@ -0,0 +1,117 @@
+package vnc
+
+import (
+	"io"
+	"net"
+
+	"golang.org/x/net/context"
+)
+
+// A WritableServerMessage is a ServerMessage that can also serialize
+// itself back onto the wire. ServerMessage itself is read-only (Type/Read),
+// since a plain ClientConn never needs to send one; Proxy needs the extra
+// Write to re-encode a message read from the upstream server before
+// relaying it to the downstream viewer.
+type WritableServerMessage interface {
+	ServerMessage
+	Write(io.Writer) error
+}
+
+// A Proxy pairs an upstream ClientConn, dialed against the real VNC server,
+// with a downstream ServerConn accepting a viewer, and forwards messages
+// between the two. This mirrors the man-in-the-middle pattern used by
+// vnc2video's proxy example: every ServerMessage read from the upstream
+// server is handed to the downstream viewer, and every ClientMessage read
+// from the downstream viewer is handed to the upstream server, with a
+// channel in between so callers can observe or rewrite messages in transit.
+type Proxy struct {
+	Upstream   *ClientConn
+	Downstream *ServerConn
+
+	serverMessageCh chan ServerMessage
+	clientMessageCh chan ClientMessage
+}
+
+// NewProxy dials upstreamAddr as a VNC client and accepts a single
+// downstream viewer on downstream, wiring the two together. The returned
+// Proxy's Run method must be called to begin forwarding messages.
+func NewProxy(ctx context.Context, upstreamAddr string, upstreamCfg *ClientConfig, downstream net.Conn, downstreamCfg *ServerConfig) (*Proxy, error) {
+	uc, err := net.Dial("tcp", upstreamAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	serverMessageCh := make(chan ServerMessage, 64)
+	clientMessageCh := make(chan ClientMessage, 64)
+	upstreamCfg.ServerMessageCh = serverMessageCh
+	downstreamCfg.ClientMessageCh = clientMessageCh
+
+	upstream, err := Connect(ctx, uc, upstreamCfg)
+	if err != nil {
+		uc.Close()
+		return nil, err
+	}
+
+	downstreamConn, err := Serve(ctx, downstream, downstreamCfg)
+	if err != nil {
+		upstream.Close()
+		return nil, err
+	}
+
+	return &Proxy{
+		Upstream:        upstream,
+		Downstream:      downstreamConn,
+		serverMessageCh: serverMessageCh,
+		clientMessageCh: clientMessageCh,
+	}, nil
+}
+
+// Run forwards messages between the upstream server and the downstream
+// viewer until ctx is cancelled or either connection is lost. Run is what
+// starts Upstream.ListenAndHandle; callers that want to observe or mutate
+// messages in flight instead of calling Run must start it themselves, read
+// from ServerMessages/ClientMessages, and forward them on manually.
+func (p *Proxy) Run(ctx context.Context) error {
+	go p.Upstream.ListenAndHandle(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.Upstream.Close()
+			p.Downstream.Close()
+			return ctx.Err()
+		case msg, ok := <-p.serverMessageCh:
+			if !ok {
+				return nil
+			}
+			wmsg, ok := msg.(WritableServerMessage)
+			if !ok {
+				return NewVNCError("proxy: server message type cannot be re-encoded for forwarding")
+			}
+			if err := wmsg.Write(p.Downstream.c); err != nil {
+				return err
+			}
+		case msg, ok := <-p.clientMessageCh:
+			if !ok {
+				return nil
+			}
+			if err := msg.Write(p.Upstream.c); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// ServerMessages returns the channel of messages read from the upstream
+// VNC server, for callers that want to inspect or filter them before they
+// reach the downstream viewer.
+func (p *Proxy) ServerMessages() <-chan ServerMessage {
+	return p.serverMessageCh
+}
+
+// ClientMessages returns the channel of messages read from the downstream
+// viewer, for callers that want to inspect or filter them before they reach
+// the upstream server.
+func (p *Proxy) ClientMessages() <-chan ClientMessage {
+	return p.clientMessageCh
+}
@@ -0,0 +1,98 @@
+/*
+Package recorder turns a vnc.ClientConn's framebuffer into either periodic
+image snapshots or a stream of raw frames suitable for piping into ffmpeg,
+enabling a VNC session to be recorded to video.
+*/
+package recorder
+
+import (
+	"bufio"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"time"
+
+	"github.com/nullcore1024/go-vnc"
+)
+
+// Format selects the still-image encoding used by Recorder.Snapshot.
+type Format int
+
+const (
+	// PNG produces lossless snapshots.
+	PNG Format = iota
+	// JPEG produces smaller, lossy snapshots.
+	JPEG
+)
+
+// A Recorder reads back a ClientConn's framebuffer on a schedule and
+// encodes it as still images or a raw frame stream. It doesn't need to see
+// individual server messages: Encoding.Read paints conn.Canvas directly as
+// it parses each FramebufferUpdate, so the canvas is always current by the
+// time this package reads it. Callers are responsible for keeping
+// conn.ListenAndHandle running to drive that parsing.
+type Recorder struct {
+	conn   *vnc.ClientConn
+	format Format
+}
+
+// New returns a Recorder that reads conn.Canvas, which must already be
+// allocated — Connect does this once the handshake completes.
+func New(conn *vnc.ClientConn, format Format) *Recorder {
+	return &Recorder{conn: conn, format: format}
+}
+
+// Snapshot encodes the current framebuffer as a single still image in the
+// Recorder's configured Format.
+func (rec *Recorder) Snapshot(w io.Writer) error {
+	img := rec.conn.Canvas.Snapshot()
+
+	switch rec.format {
+	case JPEG:
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: 90})
+	default:
+		return png.Encode(w, img)
+	}
+}
+
+// SnapshotLoop writes a snapshot to sink every interval until stop is
+// closed, for consumers that want a simple periodic-screenshot recording
+// rather than a full frame stream.
+func (rec *Recorder) SnapshotLoop(sink io.Writer, interval time.Duration, stop <-chan struct{}) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+			if err := rec.Snapshot(sink); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// StreamRaw writes the framebuffer to sink as a continuous sequence of raw
+// RGBA frames, each interval, suitable for piping into ffmpeg with
+// `-f rawvideo -pix_fmt rgba -s WxH`.
+func (rec *Recorder) StreamRaw(sink io.Writer, interval time.Duration, stop <-chan struct{}) error {
+	bw := bufio.NewWriter(sink)
+	defer bw.Flush()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return bw.Flush()
+		case <-ticker.C:
+			img := rec.conn.Canvas.Snapshot()
+			if _, err := bw.Write(img.Pix); err != nil {
+				return err
+			}
+		}
+	}
+}
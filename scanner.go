@@ -0,0 +1,196 @@
+package vnc
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// A SecurityResultError is returned when the server completes the
+// security handshake but reports failure, as opposed to a transport or
+// protocol error. Reason holds the UTF-8 string the server sent describing
+// why, when the negotiated security type provides one (e.g. VNC auth).
+type SecurityResultError struct {
+	Reason string
+}
+
+func (e *SecurityResultError) Error() string {
+	if e.Reason == "" {
+		return "vnc: security handshake failed"
+	}
+	return "vnc: security handshake failed: " + e.Reason
+}
+
+// ScanOptions configures TryPasswords.
+type ScanOptions struct {
+	// Concurrency is the maximum number of connection attempts in
+	// flight at once. If zero, attempts run one at a time.
+	Concurrency int
+
+	// AttemptTimeout bounds a single dial-and-handshake attempt,
+	// derived from the ctx passed to TryPasswords if unset.
+	AttemptTimeout time.Duration
+
+	// Backoff is the delay observed between attempts against the same
+	// address, to avoid tripping server lockouts.
+	Backoff time.Duration
+
+	// Username is passed to Auth for the security types (ARD, MS-Logon
+	// II) that authenticate a username alongside the password.
+	Username string
+
+	// Auth builds the ClientAuth list offered for a single password
+	// attempt. If nil, TryPasswords offers only ClientAuthVNC, the plain
+	// VNC DES challenge-response. Set this to exercise the other auth
+	// types this package supports, e.g.:
+	//
+	//	opts.Auth = func(username, password string) []ClientAuth {
+	//		return []ClientAuth{&ClientAuthVeNCrypt{password}}
+	//	}
+	Auth func(username, password string) []ClientAuth
+}
+
+// defaultScanAuth offers plain VNC Authentication, as used when
+// ScanOptions.Auth is unset.
+func defaultScanAuth(_, password string) []ClientAuth {
+	return []ClientAuth{&ClientAuthVNC{password}}
+}
+
+// An Attempt records the outcome of trying a single password against a
+// target address.
+type Attempt struct {
+	Password string
+
+	// Success is true if the server accepted the password.
+	Success bool
+
+	// Reason holds the SecurityResult failure reason string sent by the
+	// server, when the server explicitly rejected the password rather
+	// than the attempt failing for a transport reason.
+	Reason string
+
+	// Err holds a transport or protocol error, distinct from the server
+	// rejecting the password. A non-nil Err means the attempt could not
+	// be completed at all, rather than completing and failing.
+	Err error
+}
+
+// A ScanResult is the outcome of running TryPasswords against a target.
+type ScanResult struct {
+	Addr     string
+	Attempts []Attempt
+
+	// Password is the first password that succeeded, if any.
+	Password string
+	Success  bool
+}
+
+// TryPasswords dials addr once per password in passwords, driving the
+// protocol version, security, and security-result handshakes for each
+// attempt, and reports which (if any) succeeded. It distinguishes a
+// rejected password (SecurityResult failed, with reason) from a transport
+// or protocol error, so callers can build credential-audit tooling on top
+// without misreporting network blips as failed passwords.
+func TryPasswords(ctx context.Context, addr string, passwords []string, opts ScanOptions) (ScanResult, error) {
+	result := ScanResult{Addr: addr, Attempts: make([]Attempt, len(passwords))}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	authFn := opts.Auth
+	if authFn == nil {
+		authFn = defaultScanAuth
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+
+	for i, password := range passwords {
+		select {
+		case <-ctx.Done():
+			result.Attempts[i] = Attempt{Password: password, Err: ctx.Err()}
+			continue
+		default:
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, password string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			attempt := tryPassword(ctx, addr, opts.Username, password, opts.AttemptTimeout, authFn)
+			result.Attempts[i] = attempt
+
+			if attempt.Success {
+				mu.Lock()
+				if !result.Success {
+					result.Success = true
+					result.Password = password
+				}
+				mu.Unlock()
+			}
+
+			if opts.Backoff > 0 {
+				time.Sleep(opts.Backoff)
+			}
+		}(i, password)
+	}
+
+	wg.Wait()
+
+	return result, nil
+}
+
+// tryPassword drives a single attempt against addr, offering the
+// ClientAuth list authFn builds for username/password, and classifies the
+// result.
+func tryPassword(ctx context.Context, addr, username, password string, timeout time.Duration, authFn func(username, password string) []ClientAuth) Attempt {
+	dialCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		dialCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	dialer := net.Dialer{}
+	nc, err := dialer.DialContext(dialCtx, "tcp", addr)
+	if err != nil {
+		return Attempt{Password: password, Err: err}
+	}
+	defer nc.Close()
+
+	// Connect does not watch ctx, so the attempt's deadline is set on the
+	// connection itself to bound the handshake as well as the dial.
+	if deadline, ok := dialCtx.Deadline(); ok {
+		nc.SetDeadline(deadline)
+	}
+
+	cfg := &ClientConfig{
+		Auth: authFn(username, password),
+	}
+
+	conn, err := Connect(dialCtx, nc, cfg)
+	if err != nil {
+		return classifyAttempt(password, err)
+	}
+	defer conn.Close()
+
+	return Attempt{Password: password, Success: true}
+}
+
+// classifyAttempt turns a Connect error into an Attempt, putting it in
+// Reason rather than Err when it's a *SecurityResultError: the server
+// completed the handshake and explicitly rejected the password, as
+// opposed to a dial failure or a protocol error that never got that far.
+func classifyAttempt(password string, err error) Attempt {
+	if secErr, ok := err.(*SecurityResultError); ok {
+		return Attempt{Password: password, Reason: secErr.Reason}
+	}
+	return Attempt{Password: password, Err: err}
+}
@@ -0,0 +1,35 @@
+package vnc
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestClassifyAttemptRejectedPassword(t *testing.T) {
+	attempt := classifyAttempt("hunter2", &SecurityResultError{Reason: "Authentication failure"})
+
+	if attempt.Err != nil {
+		t.Errorf("Err = %v, want nil for a rejected password", attempt.Err)
+	}
+	if got, want := attempt.Reason, "Authentication failure"; got != want {
+		t.Errorf("Reason = %q, want %q", got, want)
+	}
+	if attempt.Success {
+		t.Errorf("Success = true, want false")
+	}
+}
+
+func TestClassifyAttemptTransportError(t *testing.T) {
+	transportErr := errors.New("connection reset by peer")
+	attempt := classifyAttempt("hunter2", transportErr)
+
+	if attempt.Err != transportErr {
+		t.Errorf("Err = %v, want %v", attempt.Err, transportErr)
+	}
+	if attempt.Reason != "" {
+		t.Errorf("Reason = %q, want empty for a transport error", attempt.Reason)
+	}
+	if attempt.Success {
+		t.Errorf("Success = true, want false")
+	}
+}
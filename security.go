@@ -0,0 +1,29 @@
+package vnc
+
+import "encoding/binary"
+
+// securityResultHandshake reads the SecurityResult message that concludes
+// the client side of the security handshake (RFC 6143 section 7.1.3). On
+// failure it returns a *SecurityResultError carrying the server's reason
+// string, so callers such as TryPasswords can distinguish a rejected password
+// from a transport or protocol error.
+func (c *ClientConn) securityResultHandshake() error {
+	var result uint32
+	if err := binary.Read(c.c, binary.BigEndian, &result); err != nil {
+		return err
+	}
+	if result == 0 {
+		return nil
+	}
+
+	var reasonLen uint32
+	if err := binary.Read(c.c, binary.BigEndian, &reasonLen); err != nil {
+		return &SecurityResultError{}
+	}
+	reason := make([]byte, reasonLen)
+	if err := binary.Read(c.c, binary.BigEndian, reason); err != nil {
+		return &SecurityResultError{}
+	}
+
+	return &SecurityResultError{Reason: string(reason)}
+}
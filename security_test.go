@@ -0,0 +1,44 @@
+package vnc
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+)
+
+func TestClientSecurityResultHandshakeSuccess(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	go binary.Write(server, binary.BigEndian, uint32(0))
+
+	conn := &ClientConn{c: client}
+	if err := conn.securityResultHandshake(); err != nil {
+		t.Errorf("securityResultHandshake() = %v, want nil", err)
+	}
+}
+
+func TestClientSecurityResultHandshakeFailure(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	go func() {
+		binary.Write(server, binary.BigEndian, uint32(1))
+		reason := []byte("too many attempts")
+		binary.Write(server, binary.BigEndian, uint32(len(reason)))
+		binary.Write(server, binary.BigEndian, reason)
+	}()
+
+	conn := &ClientConn{c: client}
+	err := conn.securityResultHandshake()
+
+	secErr, ok := err.(*SecurityResultError)
+	if !ok {
+		t.Fatalf("securityResultHandshake() error type = %T, want *SecurityResultError", err)
+	}
+	if got, want := secErr.Reason, "too many attempts"; got != want {
+		t.Errorf("SecurityResultError.Reason = %q, want %q", got, want)
+	}
+}
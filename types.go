@@ -0,0 +1,72 @@
+package vnc
+
+import "io"
+
+// An Encoding represents a FramebufferUpdate rectangle encoding: a way of
+// compressing pixel data on the wire, such as Raw, CopyRect, or ZRLE.
+type Encoding interface {
+	// Type returns the int32 identifier for the encoding, as sent during
+	// SetEncodings and in the FramebufferUpdate rectangle header.
+	Type() int32
+
+	// Read parses one rectangle's worth of this encoding's data from r,
+	// painting it onto conn.Canvas, and returns the Encoding value to
+	// reuse for subsequent rectangles of the same type (some encodings,
+	// like ZRLE, carry state across calls).
+	Read(conn *ClientConn, rect *Rectangle, r io.Reader) (Encoding, error)
+}
+
+// A ServerMessage is sent from the VNC server to the client.
+type ServerMessage interface {
+	// Type returns the message-type byte identifying this message on
+	// the wire.
+	Type() uint8
+
+	// Read parses a message of this type from r, using conn for
+	// context such as PixelFormat, ColorMap, and Canvas.
+	Read(conn *ClientConn, r io.Reader) (ServerMessage, error)
+}
+
+// A ClientMessage is sent from the VNC client to the server.
+type ClientMessage interface {
+	// Type returns the message-type byte identifying this message on
+	// the wire.
+	Type() uint8
+
+	// Read parses a message of this type from r, using conn for
+	// context.
+	Read(conn *ServerConn, r io.Reader) (ClientMessage, error)
+
+	// Write serializes this message back onto the wire, as a client
+	// does when sending it to a server.
+	Write(w io.Writer) error
+}
+
+// A Rectangle describes a rectangular region of the framebuffer: its
+// top-left corner and its dimensions, in pixels.
+type Rectangle struct {
+	X, Y, Width, Height uint16
+}
+
+// A Color is a single color-map entry, as used by SetColorMapEntriesMessage
+// and ClientConn.ColorMap/ServerConn.ColorMap when the negotiated
+// PixelFormat is not true-color. Each component is the full 16 bits sent
+// on the wire, regardless of the display's actual bit depth.
+type Color struct {
+	R, G, B uint16
+}
+
+// A PixelFormat describes how a pixel is encoded on the wire, as
+// negotiated by SetPixelFormat and sent during the ServerInit handshake
+// ([PROTOCOL] section 7.4).
+type PixelFormat struct {
+	BPP       uint8
+	Depth     uint8
+	BigEndian bool
+	TrueColor bool
+
+	RedMax, GreenMax, BlueMax       uint16
+	RedShift, GreenShift, BlueShift uint8
+
+	padding [3]byte
+}
@@ -8,8 +8,8 @@ package vnc
 
 import (
 	"encoding/binary"
-	"fmt"
 	"net"
+	"time"
 
 	"golang.org/x/net/context"
 )
@@ -42,6 +42,8 @@ func Connect(ctx context.Context, c net.Conn, cfg *ClientConfig) (*ClientConn, e
 		return nil, err
 	}
 
+	conn.Canvas = NewCanvas(int(conn.FramebufferWidth), int(conn.FramebufferHeight))
+
 	return conn, nil
 }
 
@@ -71,6 +73,10 @@ type ClientConfig struct {
 	// This only needs to contain NEW server messages, and doesn't
 	// need to explicitly contain the RFC-required messages.
 	ServerMessages []ServerMessage
+
+	// Logger receives diagnostic output from the connection. If nil, all
+	// diagnostics are discarded.
+	Logger Logger
 }
 
 // NewClientConfig returns a populated ClientConfig.
@@ -87,7 +93,16 @@ func NewClientConfig(p string) *ClientConfig {
 			&BellMessage{},
 			&ServerCutTextMessage{},
 		},
+		Logger: noopLogger{},
+	}
+}
+
+// logger returns the configured Logger, or a no-op Logger if none was set.
+func (c *ClientConn) logger() Logger {
+	if c.config.Logger == nil {
+		return noopLogger{}
 	}
+	return c.config.Logger
 }
 
 // The ClientConn type holds client connection information.
@@ -118,11 +133,17 @@ type ClientConn struct {
 	// be modified. If you wish to set a new pixel format, use the
 	// SetPixelFormat method.
 	PixelFormat PixelFormat
+
+	// Canvas holds the current state of the remote framebuffer, painted
+	// by each Encoding's Read method as FramebufferUpdate rectangles
+	// arrive. It is allocated once the server's dimensions are known, at
+	// the end of the handshake performed by Connect.
+	Canvas *Canvas
 }
 
 // Close a connection to a VNC server.
 func (c *ClientConn) Close() error {
-	fmt.Println("VNC Client connection closed.")
+	c.logger().Infof("VNC client connection closed")
 	return c.c.Close()
 }
 
@@ -131,9 +152,17 @@ func (c *ClientConn) DesktopName() string {
 	return c.desktopName
 }
 
-// ListenAndHandle listens to a VNC server and handles server messages.
-func (c *ClientConn) ListenAndHandle() error {
+// ListenAndHandle listens to a VNC server and handles server messages until
+// ctx is cancelled or the connection is lost. The connection is closed and
+// ServerMessageCh, if set, is closed before returning, so downstream
+// consumers can observe termination. It returns ctx.Err() if ctx was what
+// ended the loop, and ErrUnsupportedMessage or ErrProtocol otherwise; if
+// ClientConfig.ServerMessages is unset it returns a VNCError at once.
+func (c *ClientConn) ListenAndHandle(ctx context.Context) error {
 	defer c.Close()
+	if c.config.ServerMessageCh != nil {
+		defer close(c.config.ServerMessageCh)
+	}
 
 	if c.config.ServerMessages == nil {
 		return NewVNCError("Client config error: ServerMessages undefined")
@@ -143,33 +172,50 @@ func (c *ClientConn) ListenAndHandle() error {
 		serverMessages[m.Type()] = m
 	}
 
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.c.SetReadDeadline(time.Now())
+		case <-done:
+		}
+	}()
+
 	for {
 		var messageType uint8
 		if err := binary.Read(c.c, binary.BigEndian, &messageType); err != nil {
-			fmt.Println("error: reading from server")
-			break
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			c.logger().Errorf("error reading from server: %v", err)
+			return ErrProtocol
 		}
 
 		msg, ok := serverMessages[messageType]
 		if !ok {
-			// Unsupported message type! Bad!
-			fmt.Printf("error: unsupported message type")
-			break
+			c.logger().Warnf("unsupported message type: %v", messageType)
+			return ErrUnsupportedMessage
 		}
 
 		parsedMsg, err := msg.Read(c, c.c)
 		if err != nil {
-			fmt.Println("error: parsing message")
-			break
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			c.logger().Errorf("error parsing message: %v", err)
+			return ErrProtocol
 		}
 
 		if c.config.ServerMessageCh == nil {
-			fmt.Println("ignoring message; no server message channel")
+			c.logger().Debugf("ignoring message; no server message channel")
 			continue
 		}
 
-		c.config.ServerMessageCh <- parsedMsg
+		select {
+		case c.config.ServerMessageCh <- parsedMsg:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
-
-	return nil
 }
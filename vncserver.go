@@ -0,0 +1,214 @@
+package vnc
+
+import (
+	"encoding/binary"
+	"net"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// A ServerConfig structure is used to configure a ServerConn. After one has
+// been passed to Serve, it must not be modified.
+type ServerConfig struct {
+	// SupportedAuth is the list of security handshake methods, in
+	// preference order, that Serve will offer to the connecting client.
+	SupportedAuth []ServerAuth
+
+	// Passwords is consulted by ServerAuthVNC (when present in
+	// SupportedAuth) to validate the client's DES challenge response.
+	Passwords []string
+
+	// DesktopName is sent to the client during the ServerInit handshake.
+	DesktopName string
+
+	// Width and Height are the dimensions of the frame buffer, in pixels,
+	// sent to the client during the ServerInit handshake.
+	Width, Height uint16
+
+	// PixelFormat is the pixel format advertised to the client. If the
+	// zero value is given, a sensible 32-bit true-color default is used.
+	PixelFormat PixelFormat
+
+	// ClientMessageCh is the channel that all messages received from the
+	// connected client will be sent on. If this is not set, then all
+	// messages will be discarded.
+	ClientMessageCh chan ClientMessage
+
+	// ClientMessages is the slice of supported messages that can be read
+	// from the client. No client message types are built in: a message of
+	// any type not listed here ends the connection, including the
+	// RFC-required ones.
+	ClientMessages []ClientMessage
+
+	// Logger receives diagnostic output from the connection. If nil, all
+	// diagnostics are discarded.
+	Logger Logger
+}
+
+// logger returns the configured Logger, or a no-op Logger if none was set.
+func (c *ServerConn) logger() Logger {
+	if c.config.Logger == nil {
+		return noopLogger{}
+	}
+	return c.config.Logger
+}
+
+// A ServerAuth implements a security handshake method offered by a
+// ServerConn, the server-side counterpart of ClientAuth.
+type ServerAuth interface {
+	// SecurityType returns the byte value uniquely identifying this
+	// security type, as defined in the RFC.
+	SecurityType() uint8
+
+	// Handshake performs the server side of the security handshake
+	// negotiation and returns an error if authentication fails.
+	Handshake(conn *ServerConn) error
+}
+
+// The ServerConn type holds the state for a single accepted VNC viewer
+// connection. It is the server-side counterpart of ClientConn.
+type ServerConn struct {
+	c      net.Conn
+	config *ServerConfig
+
+	protocolVersion string
+
+	// ColorMap is the color map sent to the client when the negotiated
+	// PixelFormat uses a color map.
+	ColorMap [256]Color
+
+	// Encodings lists the encodings the connected client supports. Serve
+	// and the client-message loop do not fill it in; it holds only what
+	// the caller sets.
+	Encodings []Encoding
+
+	// FramebufferWidth and FramebufferHeight are the dimensions
+	// advertised to the client during ServerInit.
+	FramebufferWidth  uint16
+	FramebufferHeight uint16
+
+	// PixelFormat is the pixel format currently in effect for this
+	// connection. This shouldn't be modified directly.
+	PixelFormat PixelFormat
+
+	desktopName string
+
+	// auth is the ServerAuth the client selected during securityHandshake,
+	// and authErr is the outcome of running its Handshake; both are
+	// consulted by securityResultHandshake.
+	auth    ServerAuth
+	authErr error
+}
+
+// Serve negotiates and services a single downstream VNC viewer connection,
+// performing the reverse of the handshake Connect does: ProtocolVersion,
+// Security, SecurityResult, ClientInit, and ServerInit. It then reads
+// client messages until ctx is cancelled or the connection is lost,
+// delivering them on cfg.ClientMessageCh.
+func Serve(ctx context.Context, c net.Conn, cfg *ServerConfig) (*ServerConn, error) {
+	pf := cfg.PixelFormat
+	if pf == (PixelFormat{}) {
+		pf = PixelFormat{
+			BPP: 32, Depth: 24, TrueColor: true,
+			RedMax: 255, GreenMax: 255, BlueMax: 255,
+			RedShift: 16, GreenShift: 8, BlueShift: 0,
+		}
+	}
+
+	conn := &ServerConn{
+		c:                 c,
+		config:            cfg,
+		FramebufferWidth:  cfg.Width,
+		FramebufferHeight: cfg.Height,
+		PixelFormat:       pf,
+		desktopName:       cfg.DesktopName,
+	}
+
+	if err := conn.protocolVersionHandshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := conn.securityHandshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := conn.securityResultHandshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := conn.clientInit(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := conn.serverInit(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	go conn.handle(ctx)
+
+	return conn, nil
+}
+
+// Close closes the connection to the VNC viewer.
+func (c *ServerConn) Close() error {
+	c.logger().Infof("VNC server connection closed")
+	return c.c.Close()
+}
+
+// handle reads client messages until ctx is done or the connection fails.
+func (c *ServerConn) handle(ctx context.Context) {
+	defer c.Close()
+	if c.config.ClientMessageCh != nil {
+		defer close(c.config.ClientMessageCh)
+	}
+
+	clientMessages := make(map[uint8]ClientMessage)
+	for _, m := range c.config.ClientMessages {
+		clientMessages[m.Type()] = m
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.c.SetReadDeadline(time.Now())
+		case <-done:
+		}
+	}()
+
+	for {
+		var messageType uint8
+		if err := binary.Read(c.c, binary.BigEndian, &messageType); err != nil {
+			if ctx.Err() == nil {
+				c.logger().Errorf("error reading from client: %v", err)
+			}
+			return
+		}
+
+		msg, ok := clientMessages[messageType]
+		if !ok {
+			c.logger().Warnf("unsupported client message type: %v", messageType)
+			return
+		}
+
+		parsedMsg, err := msg.Read(c, c.c)
+		if err != nil {
+			c.logger().Errorf("error parsing client message: %v", err)
+			return
+		}
+
+		if c.config.ClientMessageCh == nil {
+			c.logger().Debugf("ignoring client message; no client message channel")
+			continue
+		}
+
+		select {
+		case c.config.ClientMessageCh <- parsedMsg:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package vnc
+
+import "encoding/binary"
+
+// protocolVersionHandshake performs the server side of the ProtocolVersion
+// handshake (RFC 6143 section 7.1.1): announce RFB 3.8 and read back
+// whatever version the client proposes.
+func (c *ServerConn) protocolVersionHandshake() error {
+	if err := binary.Write(c.c, binary.BigEndian, []byte("RFB 003.008\n")); err != nil {
+		return err
+	}
+
+	var versionMsg [12]byte
+	if err := binary.Read(c.c, binary.BigEndian, &versionMsg); err != nil {
+		return err
+	}
+	c.protocolVersion = string(versionMsg[:])
+
+	return nil
+}
+
+// securityHandshake performs the server side of the Security handshake
+// (RFC 6143 section 7.1.2): offer the security types in
+// config.SupportedAuth, read back the client's choice, and run that type's
+// Handshake. The outcome is recorded in authErr, to be reported by the
+// subsequent SecurityResult message.
+func (c *ServerConn) securityHandshake() error {
+	if len(c.config.SupportedAuth) == 0 {
+		return NewVNCError("Server config error: SupportedAuth undefined")
+	}
+
+	if err := binary.Write(c.c, binary.BigEndian, uint8(len(c.config.SupportedAuth))); err != nil {
+		return err
+	}
+	for _, auth := range c.config.SupportedAuth {
+		if err := binary.Write(c.c, binary.BigEndian, auth.SecurityType()); err != nil {
+			return err
+		}
+	}
+
+	var securityType uint8
+	if err := binary.Read(c.c, binary.BigEndian, &securityType); err != nil {
+		return err
+	}
+
+	for _, auth := range c.config.SupportedAuth {
+		if auth.SecurityType() != securityType {
+			continue
+		}
+		c.auth = auth
+		c.authErr = auth.Handshake(c)
+		return nil
+	}
+
+	return NewVNCError("Server config error: client chose an unoffered security type")
+}
+
+// securityResultHandshake sends the SecurityResult message (RFC 6143
+// section 7.1.3) reporting the outcome recorded in authErr by
+// securityHandshake, including a failure reason when the chosen
+// ServerAuth's Handshake returned one.
+func (c *ServerConn) securityResultHandshake() error {
+	if c.authErr == nil {
+		return binary.Write(c.c, binary.BigEndian, uint32(0))
+	}
+
+	if err := binary.Write(c.c, binary.BigEndian, uint32(1)); err != nil {
+		return err
+	}
+
+	reason := []byte(c.authErr.Error())
+	if err := binary.Write(c.c, binary.BigEndian, uint32(len(reason))); err != nil {
+		return err
+	}
+	if err := binary.Write(c.c, binary.BigEndian, reason); err != nil {
+		return err
+	}
+
+	return c.authErr
+}
+
+// clientInit reads the ClientInit message (RFC 6143 section 7.3.1): a
+// single byte indicating whether the client wants an exclusive session.
+func (c *ServerConn) clientInit() error {
+	var shared uint8
+	return binary.Read(c.c, binary.BigEndian, &shared)
+}
+
+// serverInit sends the ServerInit message (RFC 6143 section 7.3.2): the
+// framebuffer dimensions, pixel format, and desktop name configured on
+// ServerConfig.
+func (c *ServerConn) serverInit() error {
+	if err := binary.Write(c.c, binary.BigEndian, c.FramebufferWidth); err != nil {
+		return err
+	}
+	if err := binary.Write(c.c, binary.BigEndian, c.FramebufferHeight); err != nil {
+		return err
+	}
+	if err := binary.Write(c.c, binary.BigEndian, c.PixelFormat); err != nil {
+		return err
+	}
+
+	name := []byte(c.desktopName)
+	if err := binary.Write(c.c, binary.BigEndian, uint32(len(name))); err != nil {
+		return err
+	}
+	return binary.Write(c.c, binary.BigEndian, name)
+}
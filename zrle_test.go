@@ -0,0 +1,133 @@
+package vnc
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"image/color"
+	"testing"
+)
+
+// TestZRLEEncodingSpansRectangles reproduces ZRLE as real servers send it:
+// one zlib stream, chunked across rectangles via Flush rather than Close.
+// A ZRLEEncoding that re-creates its zlib.Reader per rectangle, or reads
+// each chunk in isolation instead of feeding a single persistent stream,
+// fails to decode the second rectangle.
+func TestZRLEEncodingSpansRectangles(t *testing.T) {
+	pf := truecolor24PixelFormat(true)
+	conn := &ClientConn{Canvas: NewCanvas(128, 64), PixelFormat: pf}
+
+	var stream bytes.Buffer
+	zw := zlib.NewWriter(&stream)
+
+	zw.Write([]byte{1}) // subencoding 1: solid tile
+	zw.Write([]byte{0x00, 0xff, 0x00, 0x00})
+	zw.Flush()
+	chunk1 := append([]byte(nil), stream.Bytes()...)
+	stream.Reset()
+
+	zw.Write([]byte{1}) // subencoding 1: solid tile
+	zw.Write([]byte{0x00, 0x00, 0x00, 0xff})
+	zw.Close()
+	chunk2 := append([]byte(nil), stream.Bytes()...)
+
+	e := &ZRLEEncoding{}
+
+	rect1 := &Rectangle{X: 0, Y: 0, Width: 64, Height: 64}
+	var wire1 bytes.Buffer
+	binary.Write(&wire1, binary.BigEndian, uint32(len(chunk1)))
+	wire1.Write(chunk1)
+	if _, err := e.Read(conn, rect1, &wire1); err != nil {
+		t.Fatalf("Read(rect1) = %v", err)
+	}
+
+	rect2 := &Rectangle{X: 64, Y: 0, Width: 64, Height: 64}
+	var wire2 bytes.Buffer
+	binary.Write(&wire2, binary.BigEndian, uint32(len(chunk2)))
+	wire2.Write(chunk2)
+	if _, err := e.Read(conn, rect2, &wire2); err != nil {
+		t.Fatalf("Read(rect2) = %v", err)
+	}
+
+	if got, want := conn.Canvas.At(0, 0), (color.RGBA{R: 0xff, A: 0xff}); got != want {
+		t.Errorf("rect1 pixel = %+v, want %+v", got, want)
+	}
+	if got, want := conn.Canvas.At(64, 0), (color.RGBA{B: 0xff, A: 0xff}); got != want {
+		t.Errorf("rect2 pixel = %+v, want %+v", got, want)
+	}
+}
+
+// TestZRLEEncodingReadPackedPalette covers the packed-palette tile
+// subencoding (2-16): a small palette followed by bit-packed indices.
+func TestZRLEEncodingReadPackedPalette(t *testing.T) {
+	conn := &ClientConn{Canvas: NewCanvas(2, 2), PixelFormat: truecolor24PixelFormat(true)}
+
+	var stream bytes.Buffer
+	zw := zlib.NewWriter(&stream)
+	zw.Write([]byte{2}) // subencoding 2: packed palette, size 2
+	// palette: red, then blue
+	zw.Write([]byte{0x00, 0xff, 0x00, 0x00})
+	zw.Write([]byte{0x00, 0x00, 0x00, 0xff})
+	// packed rows: row0 = idx0,idx1; row1 = idx1,idx0
+	zw.Write([]byte{0x40, 0x80})
+	zw.Close()
+
+	e := &ZRLEEncoding{}
+	var wire bytes.Buffer
+	binary.Write(&wire, binary.BigEndian, uint32(stream.Len()))
+	wire.Write(stream.Bytes())
+
+	rect := &Rectangle{X: 0, Y: 0, Width: 2, Height: 2}
+	if _, err := e.Read(conn, rect, &wire); err != nil {
+		t.Fatalf("Read() = %v", err)
+	}
+
+	if got, want := conn.Canvas.At(0, 0), (color.RGBA{R: 0xff, A: 0xff}); got != want {
+		t.Errorf("Canvas.At(0, 0) = %+v, want %+v", got, want)
+	}
+	if got, want := conn.Canvas.At(1, 0), (color.RGBA{B: 0xff, A: 0xff}); got != want {
+		t.Errorf("Canvas.At(1, 0) = %+v, want %+v", got, want)
+	}
+	if got, want := conn.Canvas.At(0, 1), (color.RGBA{B: 0xff, A: 0xff}); got != want {
+		t.Errorf("Canvas.At(0, 1) = %+v, want %+v", got, want)
+	}
+	if got, want := conn.Canvas.At(1, 1), (color.RGBA{R: 0xff, A: 0xff}); got != want {
+		t.Errorf("Canvas.At(1, 1) = %+v, want %+v", got, want)
+	}
+}
+
+// TestZRLEEncodingReadPlainRLE covers the plain RLE tile subencoding (128):
+// pixel/run-length pairs with no palette.
+func TestZRLEEncodingReadPlainRLE(t *testing.T) {
+	conn := &ClientConn{Canvas: NewCanvas(4, 1), PixelFormat: truecolor24PixelFormat(true)}
+
+	var stream bytes.Buffer
+	zw := zlib.NewWriter(&stream)
+	zw.Write([]byte{128}) // subencoding 128: plain RLE
+	// run 1: red, length 3 (encoded as 2, +1)
+	zw.Write([]byte{0x00, 0xff, 0x00, 0x00})
+	zw.Write([]byte{2})
+	// run 2: blue, length 1 (encoded as 0, +1)
+	zw.Write([]byte{0x00, 0x00, 0x00, 0xff})
+	zw.Write([]byte{0})
+	zw.Close()
+
+	e := &ZRLEEncoding{}
+	var wire bytes.Buffer
+	binary.Write(&wire, binary.BigEndian, uint32(stream.Len()))
+	wire.Write(stream.Bytes())
+
+	rect := &Rectangle{X: 0, Y: 0, Width: 4, Height: 1}
+	if _, err := e.Read(conn, rect, &wire); err != nil {
+		t.Fatalf("Read() = %v", err)
+	}
+
+	for x := 0; x < 3; x++ {
+		if got, want := conn.Canvas.At(x, 0), (color.RGBA{R: 0xff, A: 0xff}); got != want {
+			t.Errorf("Canvas.At(%d, 0) = %+v, want %+v", x, got, want)
+		}
+	}
+	if got, want := conn.Canvas.At(3, 0), (color.RGBA{B: 0xff, A: 0xff}); got != want {
+		t.Errorf("Canvas.At(3, 0) = %+v, want %+v", got, want)
+	}
+}